@@ -4,6 +4,7 @@ import (
   "bytes"
 	"context"
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -21,7 +22,7 @@ import (
 	"github.com/Dreamacro/clash/adapter"
 	"github.com/Dreamacro/clash/adapter/provider"
 	C "github.com/Dreamacro/clash/constant"
-	"github.com/Dreamacro/clash/log"
+	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 )
 
@@ -36,6 +37,13 @@ var (
 	concurrent         = flag.Int("concurrent", 4, "download concurrent size")
   outfile            = flag.String("outfile", "result", "outfile name")
   band_thred         = flag.Float64("widthred", -0.1, "less than this value, don't output to outfile")
+	udpEnabled         = flag.Bool("udp", false, "also probe proxies with a UDP (DNS) burst, useful for Hysteria2/TUIC/WireGuard")
+	udpTarget          = flag.String("udp-target", "1.1.1.1:53", "DNS server dialed through the proxy for the UDP probe")
+	udpCount           = flag.Int("udp-count", 10, "number of DNS queries to send per proxy for the UDP probe")
+	udpInterval        = flag.Duration("udp-interval", time.Millisecond*200, "interval between DNS queries in the UDP probe")
+	serveAddr          = flag.String("serve", "", "run in daemon mode and serve Prometheus metrics on this address, e.g. :9100, instead of testing once and exiting")
+	retestInterval     = flag.Duration("interval", time.Minute*5, "re-test interval when running in daemon mode")
+	targetsConfig      = flag.String("targets", "", "path to a targets.yaml listing {name, region, url, size} endpoints to test every proxy against; replaces -l with a matrix")
 )
 
 type CProxy struct {
@@ -47,6 +55,20 @@ type Result struct {
 	Name      string
 	Bandwidth float64
 	TTFB      time.Duration
+
+	// FailureClass is the coarse stage a failed probe died at (dial, tls,
+	// http_status, timeout, download), or "" on success. It's what lets
+	// classifyFailure (serve.go) break clash_speedtest_failures_total down
+	// by class instead of lumping every failure together.
+	FailureClass string
+
+	// UDP probe fields, only populated when -udp is set. -1 means "not
+	// measured", matching the convention TestProxy already uses for a
+	// failed HTTP probe.
+	UDPLoss   float64
+	UDPRTT    time.Duration
+	UDPRTTP95 time.Duration
+	UDPJitter time.Duration
 }
 
 var (
@@ -59,13 +81,29 @@ type RawConfig struct {
 	Proxies   []map[string]any          `yaml:"proxies"`
 }
 
+// noCipherFieldTypes are proxy types that, like trojan, have no "cipher"
+// field in their Clash config shape, so loadProxies' cipher-presence check
+// must not reject them. This matters now that vless/hysteria2/tuic links
+// from subscription feeds (subscription.go) land here alongside Clash YAML.
+var noCipherFieldTypes = map[string]bool{
+	"vless":     true,
+	"hysteria":  true,
+	"hysteria2": true,
+	"tuic":      true,
+	"snell":     true,
+	"socks5":    true,
+	"http":      true,
+	"wireguard": true,
+}
+
 func main() {
 	flag.Parse()
+	initLogger()
 
 	C.UA = "clash.meta"
 
 	if *configPathConfig == "" {
-		log.Fatalln("Please specify the configuration file")
+		logger.Fatal("please specify the configuration file")
 	}
 
 	var allProxies = make(map[string]CProxy)
@@ -76,7 +114,7 @@ func main() {
 			var resp *http.Response
 			resp, err = http.Get(configPath)
 			if err != nil {
-				log.Warnln("failed to fetch config: %s", err)
+				logger.Warn("failed to fetch config", zap.String("stage", "fetch"), zap.String("config.path", configPath), zap.Error(err))
 				continue
 			}
 			body, err = io.ReadAll(resp.Body)
@@ -84,13 +122,13 @@ func main() {
 			body, err = os.ReadFile(configPath)
 		}
 		if err != nil {
-			log.Warnln("failed to read config: %s", err)
+			logger.Warn("failed to read config", zap.String("stage", "read"), zap.String("config.path", configPath), zap.Error(err))
 			continue
 		}
 
 		lps, err := loadProxies(body)
 		if err != nil {
-			log.Fatalln("Failed to convert : %s", err)
+			logger.Fatal("failed to parse config", zap.String("stage", "parse"), zap.String("config.path", configPath), zap.Error(err))
 		}
 
 		for k, p := range lps {
@@ -101,6 +139,26 @@ func main() {
 	}
 
 	filteredProxies := filterProxies(*filterRegexConfig, allProxies)
+
+	if *serveAddr != "" {
+		if *retestInterval <= 0 {
+			logger.Fatal("invalid re-test interval, must be positive", zap.Duration("interval", *retestInterval))
+		}
+		if err := runDaemon(*serveAddr, *retestInterval, allProxies, filteredProxies); err != nil {
+			logger.Fatal("daemon mode failed", zap.Error(err))
+		}
+		return
+	}
+
+	if *targetsConfig != "" {
+		targets, err := loadTargets(*targetsConfig)
+		if err != nil {
+			logger.Fatal("failed to load targets", zap.Error(err))
+		}
+		runMatrixMode(filteredProxies, allProxies, targets)
+		return
+	}
+
 	results := make([]Result, 0, len(filteredProxies))
 
 	format := "%s%-42s\t%-12s\t%-12s\033[0m\n"
@@ -110,13 +168,22 @@ func main() {
 		proxy := allProxies[name]
 		switch proxy.Type() {
 		case C.Shadowsocks, C.ShadowsocksR, C.Snell, C.Socks5, C.Http, C.Vmess, C.Vless, C.Trojan, C.Hysteria, C.Hysteria2, C.WireGuard, C.Tuic:
-			result := TestProxyConcurrent(name, proxy, *downloadSizeConfig, *timeoutConfig, *concurrent)
+			result := TestProxyConcurrent(name, proxy, *livenessObject, *downloadSizeConfig, *timeoutConfig, *concurrent)
+			if *udpEnabled {
+				udpResult := TestProxyUDP(name, proxy, *udpTarget, *udpCount, *udpInterval, *timeoutConfig)
+				result.UDPLoss = udpResult.UDPLoss
+				result.UDPRTT = udpResult.UDPRTT
+				result.UDPRTTP95 = udpResult.UDPRTTP95
+				result.UDPJitter = udpResult.UDPJitter
+			} else {
+				result.UDPLoss, result.UDPRTT, result.UDPRTTP95, result.UDPJitter = -1, -1, -1, -1
+			}
 			result.Printf(format)
 			results = append(results, *result)
 		case C.Direct, C.Reject, C.Relay, C.Selector, C.Fallback, C.URLTest, C.LoadBalance:
 			continue
 		default:
-			log.Fatalln("Unsupported proxy type: %s", proxy.Type())
+			logger.Fatal("unsupported proxy type", zap.String("proxy.name", name), zap.Any("proxy.type", proxy.Type()))
 		}
 	}
 
@@ -133,7 +200,7 @@ func main() {
 			})
 			fmt.Println("\n\n===结果按照延迟排序===")
 		default:
-			log.Fatalln("Unsupported sort field: %s", *sortField)
+			logger.Fatal("unsupported sort field", zap.String("sort", *sortField))
 		}
 		fmt.Printf(format, "", "节点", "带宽", "延迟")
 		for _, result := range results {
@@ -141,13 +208,23 @@ func main() {
 		}
 	}
 
+	if *udpEnabled {
+		fmt.Println("\n\n===UDP探测结果(DNS over proxy)===")
+		fmt.Printf("%-42s\t%-10s\t%-10s\t%-10s\t%-10s\n", "节点", "丢包率", "RTT中位数", "RTT P95", "抖动")
+		for _, result := range results {
+			fmt.Printf("%-42s\t%-10s\t%-10s\t%-10s\t%-10s\n",
+				formatName(result.Name), formatUDPLoss(result.UDPLoss), formatUDPDuration(result.UDPRTT),
+				formatUDPDuration(result.UDPRTTP95), formatUDPDuration(result.UDPJitter))
+		}
+	}
+
 	if strings.EqualFold(*output, "yaml") {
 		if err := writeNodeConfigurationToYAML(*outfile+".yaml", results, allProxies, *band_thred); err != nil {
-			log.Fatalln("Failed to write yaml: %s", err)
+			logger.Fatal("failed to write yaml", zap.Error(err))
 		}
 	} else if strings.EqualFold(*output, "csv") {
 		if err := writeToCSV(*outfile+".csv", results); err != nil {
-			log.Fatalln("Failed to write csv: %s", err)
+			logger.Fatal("failed to write csv", zap.Error(err))
 		}
 	}
 }
@@ -195,9 +272,29 @@ func loadProxies(buf []byte) (map[string]CProxy, error) {
 //  if bytes.Contains(tmp_buf, obfs_cipher) {
 //		return nil, fmt.Errorf("proxy obfs not support cipher %s ", obfs_cipher)
 //  }
-	if err := yaml.Unmarshal(tmp_buf, rawCfg); err != nil {
-    log.Warnln("Self_:Unmarshal rawCfg , err.")
-		return nil, err
+	// A SIP008 JSON document is also valid (if useless) YAML, so it
+	// unmarshals here with no error and an empty Proxies/Providers - that
+	// case needs the subscription fallback just as much as an outright
+	// parse error does. But a legitimately empty Clash YAML (e.g. a
+	// rules-only template with no proxies of its own) unmarshals the same
+	// way, so only treat "parsed but empty" as a subscription candidate
+	// when the raw buffer actually looks like one (JSON or a bare URI),
+	// rather than every empty-but-valid YAML doc.
+	err := yaml.Unmarshal(tmp_buf, rawCfg)
+	empty := len(rawCfg.Proxies) == 0 && len(rawCfg.Providers) == 0
+	if err != nil || (empty && looksLikeSubscriptionBuf(buf)) {
+    logger.Warn("config is not valid Clash YAML, trying subscription formats", zap.String("stage", "parse"))
+    // not Clash YAML: fall back to the subscription formats people
+    // actually hand us (base64/plain URI list, SIP008 JSON), parsed from
+    // the original buf since tmp_buf has had "?" stripped out of it above.
+    configs, serr := loadSubscription(buf)
+    if serr != nil {
+      if err != nil {
+        return nil, err
+      }
+      return nil, serr
+    }
+    rawCfg.Proxies = configs
 	}
 
 	proxies := make(map[string]CProxy)
@@ -211,29 +308,29 @@ func loadProxies(buf []byte) (map[string]CProxy, error) {
 	for i, config := range proxiesConfig {
     type_val, ok := config["type"]
     if !ok {
-      log.Warnln("proxy %d node type is error.", i)
+      logger.Warn("proxy missing type field", zap.String("stage", "parse"), zap.Int("proxy.index", i))
       continue
     }
     //if !bytes.Equal(type_trojan, bytes.ToLower([]byte(type_val)))
     stype, yok := type_val.(string)
     if !yok {
-      log.Warnln("proxy type uuid is illeage, proxy: %d", i)
+      logger.Warn("proxy type field is not a string", zap.String("stage", "parse"), zap.Int("proxy.index", i))
       continue
     }
-    if strings.EqualFold(type_trojan, stype) != true {
+    if strings.EqualFold(type_trojan, stype) != true && !noCipherFieldTypes[strings.ToLower(stype)] {
       val, ok := config["cipher"]
       if !ok {
-        log.Warnln("Not trojan proxy, and cipher error, proxy: %d", i)
+        logger.Warn("non-trojan proxy missing cipher field", zap.String("stage", "parse"), zap.Int("proxy.index", i))
         continue
       }
       sval, yok := val.(string)
       if yok {
         if strings.Contains(sval, cipher_str) {
-          fmt.Println("obfs cipher", cipher_str)
+          logger.Warn("proxy uses unsupported obfs cipher", zap.String("stage", "parse"), zap.Int("proxy.index", i), zap.String("error.kind", "unsupported-cipher"), zap.String("cipher", cipher_str))
           continue
         }
       } else {
-        log.Warnln("%s to string error, proxy: %d", val, i)
+        logger.Warn("proxy cipher field is not a string", zap.String("stage", "parse"), zap.Int("proxy.index", i), zap.Any("cipher", val))
       }
     }
 
@@ -243,28 +340,28 @@ func loadProxies(buf []byte) (map[string]CProxy, error) {
       if ok {
         suuid, yok := uuid_val.(string)
         if !yok {
-          log.Warnln("trojan type uuid is illeage, proxy: %d", i)
+          logger.Warn("vmess uuid field is not a string", zap.String("stage", "parse"), zap.Int("proxy.index", i))
           continue
         }
         strCount := strings.Count(suuid, "")
         if strCount != 37 {
-          log.Warnln("trojan type uuid len:%d isn't equal 37, proxy: %d, %s", strCount, i, suuid)
+          logger.Warn("vmess uuid has unexpected length", zap.String("stage", "parse"), zap.Int("proxy.index", i), zap.Int("uuid.length", strCount))
           continue
         }
       } else {
-        log.Warnln("trojan type hasn't uuid, proxy: %d", i)
+        logger.Warn("vmess proxy missing uuid field", zap.String("stage", "parse"), zap.Int("proxy.index", i))
         continue
       }
     }
 		proxy, err := adapter.ParseProxy(config)
 		if err != nil {
-      log.Warnln("proxy %d: %w", i, err)
+      logger.Warn("failed to parse proxy", zap.String("stage", "parse"), zap.Int("proxy.index", i), zap.Error(err))
       continue
 			return nil, fmt.Errorf("proxy %d: %w", i, err)
 		}
 
 		if _, exist := proxies[proxy.Name()]; exist {
-      log.Warnln("proxy %s is the duplicate name", proxy.Name())
+      logger.Warn("duplicate proxy name", zap.String("stage", "parse"), zap.String("proxy.name", proxy.Name()))
       continue
 			return nil, fmt.Errorf("proxy %s is the duplicate name", proxy.Name())
 		}
@@ -275,37 +372,37 @@ func loadProxies(buf []byte) (map[string]CProxy, error) {
   ii := 0
 	for name, config := range providersConfig {
     ii++
-    log.Warnln("Self_Provider:%d name: %s.", ii, name)
+    logger.Warn("loading proxy provider", zap.String("stage", "parse"), zap.Int("provider.index", ii), zap.String("provider.name", name))
 		if name == provider.ReservedName {
 			return nil, fmt.Errorf("can not defined a provider called `%s`", provider.ReservedName)
 		}
 
     type_val, ok := config["type"]
     if !ok {
-      log.Warnln("proxy %d node type is error.", ii)
+      logger.Warn("provider proxy missing type field", zap.String("stage", "parse"), zap.Int("proxy.index", ii))
       continue
     }
 
     stype, yok := type_val.(string)
     if !yok {
-      log.Warnln("proxy type uuid is illeage, proxy: %d", ii)
+      logger.Warn("provider proxy type field is not a string", zap.String("stage", "parse"), zap.Int("proxy.index", ii))
       continue
     }
     //if !bytes.Equal(type_trojan, bytes.ToLower([]byte(type_val))) {
-    if strings.EqualFold(type_trojan, stype) != true {
+    if strings.EqualFold(type_trojan, stype) != true && !noCipherFieldTypes[strings.ToLower(stype)] {
       val, ok := config["cipher"]
       if !ok {
-        log.Warnln("Not trojan proxy, and cipher error, proxy: %d", ii)
+        logger.Warn("non-trojan provider proxy missing cipher field", zap.String("stage", "parse"), zap.Int("proxy.index", ii))
         continue
       }
       sval, yok := val.(string)
       if yok {
         if strings.Contains(sval, cipher_str) {
-          fmt.Println("obfs cipher", cipher_str)
+          logger.Warn("provider proxy uses unsupported obfs cipher", zap.String("stage", "parse"), zap.Int("proxy.index", ii), zap.String("error.kind", "unsupported-cipher"), zap.String("cipher", cipher_str))
           continue
         }
       } else {
-        log.Warnln("%s to string error, proxy: %d", val, ii)
+        logger.Warn("provider proxy cipher field is not a string", zap.String("stage", "parse"), zap.Int("proxy.index", ii), zap.Any("cipher", val))
       }
     }
 
@@ -315,16 +412,16 @@ func loadProxies(buf []byte) (map[string]CProxy, error) {
       if ok {
         suuid, yok := uuid_val.(string)
         if !yok {
-          log.Warnln("trojan type uuid is illeage, proxy: %d", ii)
+          logger.Warn("provider vmess uuid field is not a string", zap.String("stage", "parse"), zap.Int("proxy.index", ii))
           continue
         }
         strCount := strings.Count(suuid, "")
         if strCount != 37 {
-          log.Warnln("trojan type uuid len:%d isn't equal 37, proxy: %d, %s", strCount, ii, suuid)
+          logger.Warn("provider vmess uuid has unexpected length", zap.String("stage", "parse"), zap.Int("proxy.index", ii), zap.Int("uuid.length", strCount))
           continue
         }
       } else {
-        log.Warnln("trojan type hasn't uuid, proxy: %d", ii)
+        logger.Warn("provider vmess proxy missing uuid field", zap.String("stage", "parse"), zap.Int("proxy.index", ii))
         continue
       }
     }
@@ -352,7 +449,7 @@ func (r *Result) Printf(format string) {
 	fmt.Printf(format, color, formatName(r.Name), formatBandwidth(r.Bandwidth), formatMilliseconds(r.TTFB))
 }
 
-func TestProxyConcurrent(name string, proxy C.Proxy, downloadSize int, timeout time.Duration, concurrentCount int) *Result {
+func TestProxyConcurrent(name string, proxy C.Proxy, livenessURL string, downloadSize int, timeout time.Duration, concurrentCount int) *Result {
 	if concurrentCount <= 0 {
 		concurrentCount = 1
 	}
@@ -362,14 +459,22 @@ func TestProxyConcurrent(name string, proxy C.Proxy, downloadSize int, timeout t
 	downloaded := int64(0)
 
 	var wg sync.WaitGroup
+	var failureMu sync.Mutex
+	var failureClass string
 	start := time.Now()
 	for i := 0; i < concurrentCount; i++ {
 		wg.Add(1)
 		go func(i int) {
-			result, w := TestProxy(name, proxy, chunkSize, timeout)
+			result, w := TestProxy(name, proxy, livenessURL, chunkSize, timeout)
 			if w != 0 {
 				atomic.AddInt64(&downloaded, w)
 				atomic.AddInt64(&totalTTFB, int64(result.TTFB))
+			} else {
+				failureMu.Lock()
+				if failureClass == "" {
+					failureClass = result.FailureClass
+				}
+				failureMu.Unlock()
 			}
 			wg.Done()
 		}(i)
@@ -382,11 +487,15 @@ func TestProxyConcurrent(name string, proxy C.Proxy, downloadSize int, timeout t
 		Bandwidth: float64(downloaded) / downloadTime.Seconds(),
 		TTFB:      time.Duration(totalTTFB / int64(concurrentCount)),
 	}
+	if downloaded == 0 {
+		result.FailureClass = failureClass
+	}
 
+	logProxySummary(name, proxy.Type().String(), result, downloaded)
 	return result
 }
 
-func TestProxy(name string, proxy C.Proxy, downloadSize int, timeout time.Duration) (*Result, int64) {
+func TestProxy(name string, proxy C.Proxy, livenessURL string, downloadSize int, timeout time.Duration) (*Result, int64) {
 	client := http.Client{
 		Timeout: timeout,
 		Transport: &http.Transport{
@@ -408,24 +517,49 @@ func TestProxy(name string, proxy C.Proxy, downloadSize int, timeout time.Durati
 	}
 
 	start := time.Now()
-	resp, err := client.Get(fmt.Sprintf(*livenessObject, downloadSize))
+	resp, err := client.Get(fmt.Sprintf(livenessURL, downloadSize))
 	if err != nil {
-		return &Result{name, -1, -1}, 0
+		class := classifyProbeError("dial", err)
+		logger.Warn("proxy probe failed", zap.String("proxy.name", name), zap.String("stage", "dial"), zap.String("error.class", class), zap.Error(err))
+		return &Result{Name: name, Bandwidth: -1, TTFB: -1, FailureClass: class}, 0
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode-http.StatusOK > 100 {
-		return &Result{name, -1, -1}, 0
+		logger.Warn("proxy probe failed", zap.String("proxy.name", name), zap.String("stage", "ttfb"), zap.Int("http.status", resp.StatusCode))
+		return &Result{Name: name, Bandwidth: -1, TTFB: -1, FailureClass: "http_status"}, 0
 	}
 	ttfb := time.Since(start)
 
-	written, _ := io.Copy(io.Discard, resp.Body)
+	written, cerr := io.Copy(io.Discard, resp.Body)
 	if written == 0 {
-		return &Result{name, -1, -1}, 0
+		class := classifyProbeError("download", cerr)
+		logger.Warn("proxy probe failed", zap.String("proxy.name", name), zap.String("stage", "download"), zap.String("error.class", class), zap.Duration("ttfb", ttfb))
+		return &Result{Name: name, Bandwidth: -1, TTFB: -1, FailureClass: class}, 0
 	}
 	downloadTime := time.Since(start) - ttfb
 	bandwidth := float64(written) / downloadTime.Seconds()
 
-	return &Result{name, bandwidth, ttfb}, written
+	return &Result{Name: name, Bandwidth: bandwidth, TTFB: ttfb}, written
+}
+
+// classifyProbeError turns a dial/download-stage error into a coarse
+// failure class: "timeout" for anything that timed out, "tls" for a
+// handshake/certificate failure, or the stage name itself (dial/download)
+// otherwise. http_status failures are classified inline at the call site
+// since they don't come from an error.
+func classifyProbeError(stage string, err error) string {
+	if err == nil {
+		return stage
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "tls:") || strings.Contains(msg, "x509:") || strings.Contains(msg, "certificate") {
+		return "tls"
+	}
+	return stage
 }
 
 var (
@@ -469,12 +603,20 @@ func formatMilliseconds(v time.Duration) string {
 	return fmt.Sprintf("%.02fms", float64(v.Milliseconds()))
 }
 
-func writeNodeConfigurationToYAML(filePath string, results []Result, proxies map[string]CProxy, band_thred float64) error {
-	fp, err := os.Create(filePath)
-	if err != nil {
-		return err
+// buildNodeConfigurationYAML renders the above-threshold proxies as Clash
+// YAML, optionally preceded by a UDP-probe comment header. It's shared by
+// writeNodeConfigurationToYAML and the daemon's /proxies.yaml endpoint so
+// the two never drift out of sync.
+func buildNodeConfigurationYAML(results []Result, proxies map[string]CProxy, band_thred float64) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if *udpEnabled {
+		for _, result := range results {
+			fmt.Fprintf(&buf, "# %s: udp_loss=%s udp_rtt=%s udp_rtt_p95=%s udp_jitter=%s\n",
+				formatName(result.Name), formatUDPLoss(result.UDPLoss), formatUDPDuration(result.UDPRTT),
+				formatUDPDuration(result.UDPRTTP95), formatUDPDuration(result.UDPJitter))
+		}
 	}
-	defer fp.Close()
 
 	var sortedProxies []any
 	for _, result := range results {
@@ -486,12 +628,27 @@ func writeNodeConfigurationToYAML(filePath string, results []Result, proxies map
 		}
 	}
 
-	bytes, err := yaml.Marshal(sortedProxies)
+	marshaled, err := yaml.Marshal(sortedProxies)
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(marshaled)
+	return buf.Bytes(), nil
+}
+
+func writeNodeConfigurationToYAML(filePath string, results []Result, proxies map[string]CProxy, band_thred float64) error {
+	fp, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	content, err := buildNodeConfigurationYAML(results, proxies, band_thred)
 	if err != nil {
 		return err
 	}
 
-	_, err = fp.Write(bytes)
+	_, err = fp.Write(content)
 	return err
 }
 
@@ -506,7 +663,7 @@ func writeToCSV(filePath string, results []Result) error {
 	csvFile.WriteString("\xEF\xBB\xBF")
 
 	csvWriter := csv.NewWriter(csvFile)
-	err = csvWriter.Write([]string{"节点", "带宽 (MB/s)", "延迟 (ms)"})
+	err = csvWriter.Write([]string{"节点", "带宽 (MB/s)", "延迟 (ms)", "UDP丢包率", "UDP RTT中位数 (ms)", "UDP RTT P95 (ms)", "UDP抖动 (ms)"})
 	if err != nil {
 		return err
 	}
@@ -515,6 +672,10 @@ func writeToCSV(filePath string, results []Result) error {
 			result.Name,
 			fmt.Sprintf("%.2f", result.Bandwidth/1024/1024),
 			strconv.FormatInt(result.TTFB.Milliseconds(), 10),
+			formatUDPLoss(result.UDPLoss),
+			formatUDPDuration(result.UDPRTT),
+			formatUDPDuration(result.UDPRTTP95),
+			formatUDPDuration(result.UDPJitter),
 		}
 		err = csvWriter.Write(line)
 		if err != nil {
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	C "github.com/Dreamacro/clash/constant"
+	"go.uber.org/zap"
+)
+
+var (
+	bandwidthGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "clash_speedtest_bandwidth_bytes_per_second",
+		Help: "Last measured download bandwidth for a proxy, in bytes per second.",
+	}, []string{"name"})
+	ttfbGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "clash_speedtest_ttfb_seconds",
+		Help: "Last measured time-to-first-byte for a proxy, in seconds.",
+	}, []string{"name"})
+	lastSuccessGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "clash_speedtest_last_success_timestamp",
+		Help: "Unix timestamp of the last successful test for a proxy.",
+	}, []string{"name"})
+	failureCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "clash_speedtest_failures_total",
+		Help: "Number of failed tests for a proxy, by error class.",
+	}, []string{"name", "class"})
+)
+
+func init() {
+	prometheus.MustRegister(bandwidthGauge, ttfbGauge, lastSuccessGauge, failureCounter)
+}
+
+// daemonState holds the most recent test round's results so /proxies.yaml
+// and /healthz can be served without blocking on an in-flight round.
+type daemonState struct {
+	mu      sync.RWMutex
+	results []Result
+	proxies map[string]CProxy
+	started time.Time
+}
+
+// runDaemon keeps proxies loaded in memory, re-testing them on interval,
+// and serves Prometheus metrics plus a live best-N provider feed so
+// downstream Clash/Mihomo instances can pull it directly instead of this
+// tool being run as a one-shot CLI.
+func runDaemon(addr string, interval time.Duration, proxies map[string]CProxy, names []string) error {
+	state := &daemonState{proxies: proxies, started: time.Now()}
+
+	runOnce := func() {
+		results := make([]Result, 0, len(names))
+		for _, name := range names {
+			proxy := proxies[name]
+			switch proxy.Type() {
+			case C.Shadowsocks, C.ShadowsocksR, C.Snell, C.Socks5, C.Http, C.Vmess, C.Vless, C.Trojan, C.Hysteria, C.Hysteria2, C.WireGuard, C.Tuic:
+			default:
+				continue
+			}
+
+			result := TestProxyConcurrent(name, proxy, *livenessObject, *downloadSizeConfig, *timeoutConfig, *concurrent)
+			if *udpEnabled {
+				udpResult := TestProxyUDP(name, proxy, *udpTarget, *udpCount, *udpInterval, *timeoutConfig)
+				result.UDPLoss, result.UDPRTT, result.UDPRTTP95, result.UDPJitter =
+					udpResult.UDPLoss, udpResult.UDPRTT, udpResult.UDPRTTP95, udpResult.UDPJitter
+			}
+			reportResult(name, result)
+			results = append(results, *result)
+		}
+
+		state.mu.Lock()
+		state.results = results
+		state.mu.Unlock()
+		logger.Info("daemon: re-tested proxies", zap.Int("proxy.count", len(results)))
+	}
+
+	runOnce()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			runOnce()
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "ok, uptime=%s\n", time.Since(state.started))
+	})
+	mux.HandleFunc("/proxies.yaml", func(w http.ResponseWriter, r *http.Request) {
+		state.mu.RLock()
+		results, proxies := state.results, state.proxies
+		state.mu.RUnlock()
+
+		content, err := buildNodeConfigurationYAML(results, proxies, *band_thred)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(content)
+	})
+
+	logger.Info("serving metrics", zap.String("addr", addr))
+	return http.ListenAndServe(addr, mux)
+}
+
+func reportResult(name string, result *Result) {
+	if result.Bandwidth > 0 {
+		bandwidthGauge.WithLabelValues(name).Set(result.Bandwidth)
+		lastSuccessGauge.WithLabelValues(name).Set(float64(time.Now().Unix()))
+	} else {
+		failureCounter.WithLabelValues(name, classifyFailure(result)).Inc()
+	}
+	if result.TTFB > 0 {
+		ttfbGauge.WithLabelValues(name).Set(result.TTFB.Seconds())
+	}
+}
+
+func classifyFailure(result *Result) string {
+	if result.FailureClass != "" {
+		return result.FailureClass
+	}
+	return "probe"
+}
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// sip008Config is the JSON document shape described by the SIP008 spec:
+// https://shadowsocks.org/guide/sip008.html
+type sip008Config struct {
+	Servers []sip008Server `json:"servers"`
+}
+
+type sip008Server struct {
+	Remarks    string `json:"remarks"`
+	Server     string `json:"server"`
+	ServerPort int    `json:"server_port"`
+	Password   string `json:"password"`
+	Method     string `json:"method"`
+}
+
+// loadSubscription recognizes the subscription formats people actually hand
+// to this tool when they don't have a Clash YAML lying around: a
+// base64-encoded (or plain) line-delimited list of ss/ssr/vmess/trojan/
+// vless/hysteria2/tuic URIs, or a SIP008 JSON document. It's tried when
+// Clash YAML parsing fails, or when the YAML holds no proxies or providers
+// and the buffer looks like a subscription (looksLikeSubscriptionBuf).
+func loadSubscription(buf []byte) ([]map[string]any, error) {
+	if configs, err := loadURIList(buf); err == nil && len(configs) > 0 {
+		return configs, nil
+	}
+	if configs, err := loadSIP008(buf); err == nil && len(configs) > 0 {
+		return configs, nil
+	}
+	return nil, fmt.Errorf("not a recognized subscription format")
+}
+
+func loadSIP008(buf []byte) ([]map[string]any, error) {
+	var cfg sip008Config
+	if err := json.Unmarshal(buf, &cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Servers) == 0 {
+		return nil, fmt.Errorf("sip008: no servers")
+	}
+
+	configs := make([]map[string]any, 0, len(cfg.Servers))
+	for i, s := range cfg.Servers {
+		name := s.Remarks
+		if name == "" {
+			name = fmt.Sprintf("ss-%d", i)
+		}
+		configs = append(configs, map[string]any{
+			"name":     name,
+			"type":     "ss",
+			"server":   s.Server,
+			"port":     s.ServerPort,
+			"cipher":   s.Method,
+			"password": s.Password,
+		})
+	}
+	return configs, nil
+}
+
+func loadURIList(buf []byte) ([]map[string]any, error) {
+	decoded, err := decodeSubscriptionBody(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []map[string]any
+	for i, line := range strings.Split(strings.TrimSpace(string(decoded)), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		config, err := parseProxyURI(line)
+		if err != nil {
+			logger.Warn("failed to parse subscription line", zap.String("stage", "parse"), zap.Int("line", i), zap.Error(err))
+			continue
+		}
+		configs = append(configs, config)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("uri list: no usable proxies")
+	}
+	return configs, nil
+}
+
+// subscriptionURISchemes are the share-link prefixes loadSubscription
+// recognizes as "this is a raw URI list, not base64" input.
+var subscriptionURISchemes = []string{"ss://", "ssr://", "vmess://", "trojan://", "vless://", "hysteria2://", "hy2://", "tuic://"}
+
+// looksLikeSubscriptionBuf reports whether buf is shaped like one of the
+// subscription formats loadSubscription understands (SIP008 JSON or a bare
+// URI list) rather than Clash YAML. It's used to decide whether a buffer
+// that unmarshals as valid-but-empty YAML (e.g. a SIP008 document, which is
+// also technically valid YAML) should be retried as a subscription, without
+// also catching a legitimately empty Clash YAML doc.
+func looksLikeSubscriptionBuf(buf []byte) bool {
+	trimmed := bytes.TrimSpace(buf)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return true
+	}
+	for _, scheme := range subscriptionURISchemes {
+		if bytes.HasPrefix(trimmed, []byte(scheme)) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeSubscriptionBody returns buf trimmed but otherwise unchanged when it
+// starts with a known URI scheme, so a plain (already decoded) line-delimited
+// URI list also works; otherwise it returns buf decoded from the first of the
+// std/raw-std/url/raw-url base64 alphabets that accepts it, or an error when
+// none does.
+func decodeSubscriptionBody(buf []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(buf)
+	for _, scheme := range subscriptionURISchemes {
+		if bytes.HasPrefix(trimmed, []byte(scheme)) {
+			return trimmed, nil
+		}
+	}
+
+	s := string(trimmed)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(s); err == nil {
+			return decoded, nil
+		}
+	}
+	return nil, fmt.Errorf("not valid base64 or a raw uri list")
+}
+
+func parseProxyURI(raw string) (map[string]any, error) {
+	switch {
+	case strings.HasPrefix(raw, "ss://"):
+		return parseSSURI(raw)
+	case strings.HasPrefix(raw, "ssr://"):
+		return parseSSRURI(raw)
+	case strings.HasPrefix(raw, "vmess://"):
+		return parseVmessURI(raw)
+	case strings.HasPrefix(raw, "trojan://"):
+		return parseTrojanURI(raw)
+	case strings.HasPrefix(raw, "vless://"):
+		return parseVlessURI(raw)
+	case strings.HasPrefix(raw, "hysteria2://"), strings.HasPrefix(raw, "hy2://"):
+		return parseHysteria2URI(raw)
+	case strings.HasPrefix(raw, "tuic://"):
+		return parseTuicURI(raw)
+	default:
+		return nil, fmt.Errorf("unrecognized uri scheme: %s", raw)
+	}
+}
+
+func parseSSURI(raw string) (map[string]any, error) {
+	rest := strings.TrimPrefix(raw, "ss://")
+
+	name := "ss"
+	if idx := strings.Index(rest, "#"); idx >= 0 {
+		if n, err := url.QueryUnescape(rest[idx+1:]); err == nil && n != "" {
+			name = n
+		}
+		rest = rest[:idx]
+	}
+
+	var query string
+	if idx := strings.Index(rest, "?"); idx >= 0 {
+		query = rest[idx+1:]
+		rest = rest[:idx]
+	}
+
+	var method, password, host, port string
+	if at := strings.LastIndex(rest, "@"); at >= 0 {
+		userinfo, err := decodeSubscriptionBody([]byte(rest[:at]))
+		if err != nil {
+			userinfo = []byte(rest[:at])
+		}
+		parts := strings.SplitN(string(userinfo), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("ss: bad userinfo")
+		}
+		method, password = parts[0], parts[1]
+
+		h, p, err := net.SplitHostPort(rest[at+1:])
+		if err != nil {
+			return nil, fmt.Errorf("ss: bad host:port: %w", err)
+		}
+		host, port = h, p
+	} else {
+		decoded, err := decodeSubscriptionBody([]byte(rest))
+		if err != nil {
+			return nil, fmt.Errorf("ss: bad legacy payload: %w", err)
+		}
+		at := strings.LastIndex(string(decoded), "@")
+		if at < 0 {
+			return nil, fmt.Errorf("ss: bad legacy payload")
+		}
+		parts := strings.SplitN(string(decoded[:at]), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("ss: bad legacy userinfo")
+		}
+		method, password = parts[0], parts[1]
+
+		h, p, err := net.SplitHostPort(string(decoded[at+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("ss: bad legacy host:port: %w", err)
+		}
+		host, port = h, p
+	}
+
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("ss: bad port: %w", err)
+	}
+
+	config := map[string]any{
+		"name":     name,
+		"type":     "ss",
+		"server":   host,
+		"port":     portNum,
+		"cipher":   method,
+		"password": password,
+	}
+	applyTransportOpts(config, query)
+	return config, nil
+}
+
+func parseSSRURI(raw string) (map[string]any, error) {
+	decoded, err := decodeSubscriptionBody([]byte(strings.TrimPrefix(raw, "ssr://")))
+	if err != nil {
+		return nil, fmt.Errorf("ssr: bad base64: %w", err)
+	}
+
+	main, paramStr, _ := strings.Cut(string(decoded), "/?")
+	parts := strings.SplitN(main, ":", 6)
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("ssr: malformed payload")
+	}
+	host, port, protocol, method, obfs, passB64 := parts[0], parts[1], parts[2], parts[3], parts[4], parts[5]
+
+	passwordRaw, err := decodeSubscriptionBody([]byte(passB64))
+	if err != nil {
+		return nil, fmt.Errorf("ssr: bad password: %w", err)
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("ssr: bad port: %w", err)
+	}
+
+	name := "ssr"
+	obfsParam, protocolParam := "", ""
+	if paramStr != "" {
+		if q, err := url.ParseQuery(paramStr); err == nil {
+			if remarks, derr := decodeSubscriptionBody([]byte(q.Get("remarks"))); derr == nil {
+				name = string(remarks)
+			}
+			if v, derr := decodeSubscriptionBody([]byte(q.Get("obfsparam"))); derr == nil {
+				obfsParam = string(v)
+			}
+			if v, derr := decodeSubscriptionBody([]byte(q.Get("protoparam"))); derr == nil {
+				protocolParam = string(v)
+			}
+		}
+	}
+
+	return map[string]any{
+		"name":           name,
+		"type":           "ssr",
+		"server":         host,
+		"port":           portNum,
+		"cipher":         method,
+		"password":       string(passwordRaw),
+		"protocol":       protocol,
+		"protocol-param": protocolParam,
+		"obfs":           obfs,
+		"obfs-param":     obfsParam,
+	}, nil
+}
+
+type vmessLink struct {
+	Ps   string `json:"ps"`
+	Add  string `json:"add"`
+	Port any    `json:"port"`
+	Id   string `json:"id"`
+	Aid  any    `json:"aid"`
+	Net  string `json:"net"`
+	Host string `json:"host"`
+	Path string `json:"path"`
+	TLS  string `json:"tls"`
+	SNI  string `json:"sni"`
+	ALPN string `json:"alpn"`
+}
+
+func parseVmessURI(raw string) (map[string]any, error) {
+	decoded, err := decodeSubscriptionBody([]byte(strings.TrimPrefix(raw, "vmess://")))
+	if err != nil {
+		return nil, fmt.Errorf("vmess: bad base64: %w", err)
+	}
+
+	var link vmessLink
+	if err := json.Unmarshal(decoded, &link); err != nil {
+		return nil, fmt.Errorf("vmess: bad json: %w", err)
+	}
+
+	port, err := toInt(link.Port)
+	if err != nil {
+		return nil, fmt.Errorf("vmess: bad port: %w", err)
+	}
+	alterId, _ := toInt(link.Aid)
+
+	name := link.Ps
+	if name == "" {
+		name = "vmess"
+	}
+
+	config := map[string]any{
+		"name":    name,
+		"type":    "vmess",
+		"server":  link.Add,
+		"port":    port,
+		"uuid":    link.Id,
+		"alterId": alterId,
+		"cipher":  "auto",
+	}
+	if link.Net != "" {
+		config["network"] = link.Net
+		switch link.Net {
+		case "ws":
+			wsOpts := map[string]any{}
+			if link.Path != "" {
+				wsOpts["path"] = link.Path
+			}
+			if link.Host != "" {
+				wsOpts["headers"] = map[string]any{"Host": link.Host}
+			}
+			config["ws-opts"] = wsOpts
+		case "grpc":
+			config["grpc-opts"] = map[string]any{"grpc-service-name": link.Path}
+		}
+	}
+	if strings.EqualFold(link.TLS, "tls") {
+		config["tls"] = true
+	}
+	if link.SNI != "" {
+		config["servername"] = link.SNI
+	}
+	if link.ALPN != "" {
+		config["alpn"] = strings.Split(link.ALPN, ",")
+	}
+	return config, nil
+}
+
+func toInt(v any) (int, error) {
+	switch t := v.(type) {
+	case float64:
+		return int(t), nil
+	case string:
+		return strconv.Atoi(t)
+	default:
+		return 0, fmt.Errorf("unsupported numeric type %T", v)
+	}
+}
+
+func parseTrojanURI(raw string) (map[string]any, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.User == nil {
+		return nil, fmt.Errorf("trojan: missing password")
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return nil, fmt.Errorf("trojan: bad port: %w", err)
+	}
+
+	config := map[string]any{
+		"name":     uriName(u, "trojan"),
+		"type":     "trojan",
+		"server":   u.Hostname(),
+		"port":     port,
+		"password": u.User.Username(),
+	}
+	applyTransportOpts(config, u.RawQuery)
+	return config, nil
+}
+
+func parseVlessURI(raw string) (map[string]any, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.User == nil {
+		return nil, fmt.Errorf("vless: missing uuid")
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return nil, fmt.Errorf("vless: bad port: %w", err)
+	}
+
+	config := map[string]any{
+		"name":   uriName(u, "vless"),
+		"type":   "vless",
+		"server": u.Hostname(),
+		"port":   port,
+		"uuid":   u.User.Username(),
+	}
+	applyTransportOpts(config, u.RawQuery)
+	return config, nil
+}
+
+func parseHysteria2URI(raw string) (map[string]any, error) {
+	raw = strings.Replace(raw, "hy2://", "hysteria2://", 1)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return nil, fmt.Errorf("hysteria2: bad port: %w", err)
+	}
+
+	config := map[string]any{
+		"name":     uriName(u, "hysteria2"),
+		"type":     "hysteria2",
+		"server":   u.Hostname(),
+		"port":     port,
+		"password": u.User.Username(),
+	}
+	q := u.Query()
+	if sni := q.Get("sni"); sni != "" {
+		config["sni"] = sni
+	}
+	if obfs := q.Get("obfs"); obfs != "" {
+		config["obfs"] = obfs
+	}
+	if obfsPassword := q.Get("obfs-password"); obfsPassword != "" {
+		config["obfs-password"] = obfsPassword
+	}
+	if insecure := q.Get("insecure"); insecure == "1" || strings.EqualFold(insecure, "true") {
+		config["skip-cert-verify"] = true
+	}
+	return config, nil
+}
+
+func parseTuicURI(raw string) (map[string]any, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.User == nil {
+		return nil, fmt.Errorf("tuic: missing credentials")
+	}
+	password, _ := u.User.Password()
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return nil, fmt.Errorf("tuic: bad port: %w", err)
+	}
+
+	config := map[string]any{
+		"name":     uriName(u, "tuic"),
+		"type":     "tuic",
+		"server":   u.Hostname(),
+		"port":     port,
+		"uuid":     u.User.Username(),
+		"password": password,
+	}
+	q := u.Query()
+	if sni := q.Get("sni"); sni != "" {
+		config["sni"] = sni
+	}
+	if alpn := q.Get("alpn"); alpn != "" {
+		config["alpn"] = strings.Split(alpn, ",")
+	}
+	return config, nil
+}
+
+// applyTransportOpts maps the query-string parameters common to vless/
+// trojan/vmess share links (network type, ws/grpc opts, sni, alpn, flow,
+// tls) onto the adapter.ParseProxy config shape.
+func applyTransportOpts(config map[string]any, rawQuery string) {
+	if rawQuery == "" {
+		return
+	}
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return
+	}
+
+	if network := firstNonEmpty(q.Get("type"), q.Get("net")); network != "" {
+		config["network"] = network
+		switch network {
+		case "ws":
+			wsOpts := map[string]any{}
+			if path := q.Get("path"); path != "" {
+				wsOpts["path"] = path
+			}
+			if host := q.Get("host"); host != "" {
+				wsOpts["headers"] = map[string]any{"Host": host}
+			}
+			config["ws-opts"] = wsOpts
+		case "grpc":
+			grpcOpts := map[string]any{}
+			if svc := firstNonEmpty(q.Get("serviceName"), q.Get("path")); svc != "" {
+				grpcOpts["grpc-service-name"] = svc
+			}
+			config["grpc-opts"] = grpcOpts
+		}
+	}
+
+	if sni := firstNonEmpty(q.Get("sni"), q.Get("peer")); sni != "" {
+		config["sni"] = sni
+	}
+	if alpn := q.Get("alpn"); alpn != "" {
+		config["alpn"] = strings.Split(alpn, ",")
+	}
+	if flow := q.Get("flow"); flow != "" {
+		config["flow"] = flow
+	}
+	if security := q.Get("security"); strings.EqualFold(security, "tls") || q.Get("tls") == "1" {
+		config["tls"] = true
+	}
+	if insecure := q.Get("allowInsecure"); insecure == "1" || strings.EqualFold(insecure, "true") {
+		config["skip-cert-verify"] = true
+	}
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func uriName(u *url.URL, fallback string) string {
+	if u.Fragment != "" {
+		return u.Fragment
+	}
+	return fallback
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseSSURI(t *testing.T) {
+	cases := []struct {
+		name       string
+		uri        string
+		wantServer string
+		wantPort   int
+		wantCipher string
+		wantPass   string
+		wantName   string
+	}{
+		{
+			name:       "sip002 plain userinfo",
+			uri:        "ss://YWVzLTEyOC1nY206cGFzc3dvcmQ@example.com:8388#MyNode",
+			wantServer: "example.com",
+			wantPort:   8388,
+			wantCipher: "aes-128-gcm",
+			wantPass:   "password",
+			wantName:   "MyNode",
+		},
+		{
+			name:       "legacy fully-encoded",
+			uri:        "ss://YWVzLTEyOC1nY206cGFzc3dvcmRAZXhhbXBsZS5jb206ODM4OA==#Legacy",
+			wantServer: "example.com",
+			wantPort:   8388,
+			wantCipher: "aes-128-gcm",
+			wantPass:   "password",
+			wantName:   "Legacy",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := parseSSURI(tc.uri)
+			if err != nil {
+				t.Fatalf("parseSSURI(%q) returned error: %v", tc.uri, err)
+			}
+			if config["type"] != "ss" {
+				t.Errorf("type = %v, want ss", config["type"])
+			}
+			if config["server"] != tc.wantServer {
+				t.Errorf("server = %v, want %v", config["server"], tc.wantServer)
+			}
+			if config["port"] != tc.wantPort {
+				t.Errorf("port = %v, want %v", config["port"], tc.wantPort)
+			}
+			if config["cipher"] != tc.wantCipher {
+				t.Errorf("cipher = %v, want %v", config["cipher"], tc.wantCipher)
+			}
+			if config["password"] != tc.wantPass {
+				t.Errorf("password = %v, want %v", config["password"], tc.wantPass)
+			}
+			if config["name"] != tc.wantName {
+				t.Errorf("name = %v, want %v", config["name"], tc.wantName)
+			}
+		})
+	}
+}
+
+func TestParseSSURIErrors(t *testing.T) {
+	if _, err := parseSSURI("ss://not-valid-anything"); err == nil {
+		t.Error("expected an error for an unparsable ss uri, got nil")
+	}
+}
+
+func TestParseSSRURI(t *testing.T) {
+	uri := "ssr://ZXhhbXBsZS5jb206ODM4ODpvcmlnaW46YWVzLTEyOC1nY206cGxhaW46Y0dGemMzZHZjbVEvP3JlbWFya3M9VFhsT2IyUmw"
+
+	config, err := parseSSRURI(uri)
+	if err != nil {
+		t.Fatalf("parseSSRURI returned error: %v", err)
+	}
+	want := map[string]any{
+		"name":           "MyNode",
+		"type":           "ssr",
+		"server":         "example.com",
+		"port":           8388,
+		"cipher":         "aes-128-gcm",
+		"password":       "password",
+		"protocol":       "origin",
+		"protocol-param": "",
+		"obfs":           "plain",
+		"obfs-param":     "",
+	}
+	for k, v := range want {
+		if config[k] != v {
+			t.Errorf("%s = %v, want %v", k, config[k], v)
+		}
+	}
+}
+
+func TestParseSSRURIMalformed(t *testing.T) {
+	if _, err := parseSSRURI("ssr://" + "dG9vLWZldy1maWVsZHM="); err == nil {
+		t.Error("expected an error for an ssr payload without 6 colon-separated fields, got nil")
+	}
+}
+
+func TestParseVmessURI(t *testing.T) {
+	uri := "vmess://eyJwcyI6ICJNeU5vZGUiLCAiYWRkIjogImV4YW1wbGUuY29tIiwgInBvcnQiOiAiNDQzIiwgImlkIjogIjExMTExMTExLTExMTEtMTExMS0xMTExLTExMTExMTExMTExMSIsICJhaWQiOiAiMCIsICJuZXQiOiAid3MiLCAiaG9zdCI6ICJjZG4uZXhhbXBsZS5jb20iLCAicGF0aCI6ICIvd3MiLCAidGxzIjogInRscyJ9"
+
+	config, err := parseVmessURI(uri)
+	if err != nil {
+		t.Fatalf("parseVmessURI returned error: %v", err)
+	}
+	if config["server"] != "example.com" {
+		t.Errorf("server = %v, want example.com", config["server"])
+	}
+	if config["port"] != 443 {
+		t.Errorf("port = %v, want 443 (string port must coerce to int)", config["port"])
+	}
+	if config["uuid"] != "11111111-1111-1111-1111-111111111111" {
+		t.Errorf("uuid = %v", config["uuid"])
+	}
+	if config["alterId"] != 0 {
+		t.Errorf("alterId = %v, want 0 (string aid must coerce to int)", config["alterId"])
+	}
+	if config["tls"] != true {
+		t.Errorf("tls = %v, want true", config["tls"])
+	}
+	wsOpts, ok := config["ws-opts"].(map[string]any)
+	if !ok {
+		t.Fatalf("ws-opts missing or wrong type: %#v", config["ws-opts"])
+	}
+	if wsOpts["path"] != "/ws" {
+		t.Errorf("ws-opts.path = %v, want /ws", wsOpts["path"])
+	}
+}
+
+func TestParseVmessURINumericPort(t *testing.T) {
+	// port as a JSON number rather than string must also coerce via toInt.
+	config, err := parseVmessURI("vmess://eyJhZGQiOiAiaG9zdCIsICJwb3J0IjogNDQzLCAiaWQiOiAieCJ9")
+	if err != nil {
+		t.Fatalf("parseVmessURI returned error: %v", err)
+	}
+	if config["port"] != 443 {
+		t.Errorf("port = %v, want 443 (numeric json port)", config["port"])
+	}
+}
+
+func TestParseTrojanURI(t *testing.T) {
+	config, err := parseTrojanURI("trojan://secret@example.com:443?sni=sni.example.com#MyNode")
+	if err != nil {
+		t.Fatalf("parseTrojanURI returned error: %v", err)
+	}
+	if config["password"] != "secret" {
+		t.Errorf("password = %v, want secret", config["password"])
+	}
+	if config["server"] != "example.com" || config["port"] != 443 {
+		t.Errorf("server/port = %v/%v", config["server"], config["port"])
+	}
+	if config["sni"] != "sni.example.com" {
+		t.Errorf("sni = %v, want sni.example.com", config["sni"])
+	}
+	if config["name"] != "MyNode" {
+		t.Errorf("name = %v, want MyNode", config["name"])
+	}
+}
+
+func TestParseTrojanURIMissingPassword(t *testing.T) {
+	if _, err := parseTrojanURI("trojan://example.com:443"); err == nil {
+		t.Error("expected an error when the trojan uri has no userinfo, got nil")
+	}
+}
+
+func TestParseVlessURI(t *testing.T) {
+	config, err := parseVlessURI("vless://11111111-1111-1111-1111-111111111111@example.com:443?type=grpc&serviceName=svc&security=tls#MyNode")
+	if err != nil {
+		t.Fatalf("parseVlessURI returned error: %v", err)
+	}
+	if config["uuid"] != "11111111-1111-1111-1111-111111111111" {
+		t.Errorf("uuid = %v", config["uuid"])
+	}
+	if config["network"] != "grpc" {
+		t.Errorf("network = %v, want grpc", config["network"])
+	}
+	grpcOpts, ok := config["grpc-opts"].(map[string]any)
+	if !ok {
+		t.Fatalf("grpc-opts missing or wrong type: %#v", config["grpc-opts"])
+	}
+	if grpcOpts["grpc-service-name"] != "svc" {
+		t.Errorf("grpc-service-name = %v, want svc", grpcOpts["grpc-service-name"])
+	}
+	if config["tls"] != true {
+		t.Errorf("tls = %v, want true", config["tls"])
+	}
+}
+
+func TestParseHysteria2URI(t *testing.T) {
+	cases := []string{
+		"hysteria2://pw@example.com:443?sni=sni.example.com&obfs=salamander&obfs-password=op&insecure=1#MyNode",
+		"hy2://pw@example.com:443?sni=sni.example.com&obfs=salamander&obfs-password=op&insecure=1#MyNode",
+	}
+	for _, uri := range cases {
+		config, err := parseHysteria2URI(uri)
+		if err != nil {
+			t.Fatalf("parseHysteria2URI(%q) returned error: %v", uri, err)
+		}
+		if config["type"] != "hysteria2" {
+			t.Errorf("type = %v, want hysteria2", config["type"])
+		}
+		if config["password"] != "pw" {
+			t.Errorf("password = %v, want pw", config["password"])
+		}
+		if config["obfs"] != "salamander" || config["obfs-password"] != "op" {
+			t.Errorf("obfs/obfs-password = %v/%v", config["obfs"], config["obfs-password"])
+		}
+		if config["skip-cert-verify"] != true {
+			t.Errorf("skip-cert-verify = %v, want true", config["skip-cert-verify"])
+		}
+	}
+}
+
+func TestParseTuicURI(t *testing.T) {
+	config, err := parseTuicURI("tuic://uuid:pw@example.com:443?sni=sni.example.com&alpn=h3,h3-29#MyNode")
+	if err != nil {
+		t.Fatalf("parseTuicURI returned error: %v", err)
+	}
+	if config["uuid"] != "uuid" || config["password"] != "pw" {
+		t.Errorf("uuid/password = %v/%v", config["uuid"], config["password"])
+	}
+	alpn, ok := config["alpn"].([]string)
+	if !ok || len(alpn) != 2 || alpn[0] != "h3" || alpn[1] != "h3-29" {
+		t.Errorf("alpn = %#v, want [h3 h3-29]", config["alpn"])
+	}
+}
+
+func TestParseTuicURIMissingCredentials(t *testing.T) {
+	if _, err := parseTuicURI("tuic://example.com:443"); err == nil {
+		t.Error("expected an error when the tuic uri has no userinfo, got nil")
+	}
+}
+
+func TestLoadSIP008(t *testing.T) {
+	doc := []byte(`{"servers":[{"remarks":"Node1","server":"example.com","server_port":8388,"password":"pw","method":"aes-128-gcm"}]}`)
+
+	configs, err := loadSIP008(doc)
+	if err != nil {
+		t.Fatalf("loadSIP008 returned error: %v", err)
+	}
+	if len(configs) != 1 {
+		t.Fatalf("got %d configs, want 1", len(configs))
+	}
+	if configs[0]["name"] != "Node1" || configs[0]["server"] != "example.com" || configs[0]["port"] != 8388 {
+		t.Errorf("unexpected config: %#v", configs[0])
+	}
+}
+
+func TestLoadSIP008NotJSON(t *testing.T) {
+	if _, err := loadSIP008([]byte("proxies:\n  - name: a\n")); err == nil {
+		t.Error("expected an error for non-JSON input, got nil")
+	}
+}
+
+func TestLoadURIList(t *testing.T) {
+	body := []byte("ss://YWVzLTEyOC1nY206cGFzc3dvcmQ@example.com:8388#Node1\ntrojan://secret@example.com:443#Node2\n")
+
+	configs, err := loadURIList(body)
+	if err != nil {
+		t.Fatalf("loadURIList returned error: %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("got %d configs, want 2", len(configs))
+	}
+	if configs[0]["type"] != "ss" || configs[1]["type"] != "trojan" {
+		t.Errorf("unexpected types: %v, %v", configs[0]["type"], configs[1]["type"])
+	}
+}
+
+func TestDecodeSubscriptionBody(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"raw uri passthrough", "ss://foo", "ss://foo"},
+		{"std base64", "aGVsbG8=", "hello"},
+		{"base64 no padding", "aGVsbG8", "hello"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decodeSubscriptionBody([]byte(tc.in))
+			if err != nil {
+				t.Fatalf("decodeSubscriptionBody(%q) returned error: %v", tc.in, err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLooksLikeSubscriptionBuf(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want bool
+	}{
+		{"sip008 json", `{"servers":[]}`, true},
+		{"ss uri", "ss://foo", true},
+		{"clash yaml", "proxies:\n  - name: a\n", false},
+		{"rules-only yaml", "rules:\n  - MATCH,DIRECT\n", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := looksLikeSubscriptionBuf([]byte(tc.in)); got != tc.want {
+				t.Errorf("looksLikeSubscriptionBuf(%q) = %v, want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
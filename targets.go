@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	C "github.com/Dreamacro/clash/constant"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Target is one geo endpoint in a -targets matrix, e.g. a Cloudflare POP in
+// a specific region or a user-owned origin. Size/Timeout fall back to the
+// top-level -size/-timeout flags when left zero.
+type Target struct {
+	Name    string        `yaml:"name"`
+	Region  string        `yaml:"region"`
+	URL     string        `yaml:"url"`
+	Size    int           `yaml:"size"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type targetsFile struct {
+	Targets []Target `yaml:"targets"`
+}
+
+func loadTargets(path string) ([]Target, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg targetsFile
+	if err := yaml.Unmarshal(buf, &cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("targets file %s has no targets", path)
+	}
+
+	for i := range cfg.Targets {
+		if cfg.Targets[i].Size <= 0 {
+			cfg.Targets[i].Size = *downloadSizeConfig
+		}
+		if cfg.Targets[i].Timeout <= 0 {
+			cfg.Targets[i].Timeout = *timeoutConfig
+		}
+	}
+	return cfg.Targets, nil
+}
+
+// MatrixRow is one proxy's results against every target in the matrix.
+type MatrixRow struct {
+	Name    string
+	Targets map[string]Result
+}
+
+// WorstBandwidth is the bandwidth of this proxy's weakest target, or 0 if
+// any target failed outright. Ranking on this instead of total/average
+// bandwidth surfaces nodes that are broadly usable rather than ones that
+// happen to be fast to a single CDN POP.
+func (m MatrixRow) WorstBandwidth() float64 {
+	worst := math.Inf(1)
+	seen := false
+	for _, r := range m.Targets {
+		if r.Bandwidth <= 0 {
+			return 0
+		}
+		seen = true
+		if r.Bandwidth < worst {
+			worst = r.Bandwidth
+		}
+	}
+	if !seen {
+		return 0
+	}
+	return worst
+}
+
+func (m MatrixRow) totalBandwidth() float64 {
+	total := 0.0
+	for _, r := range m.Targets {
+		if r.Bandwidth > 0 {
+			total += r.Bandwidth
+		}
+	}
+	return total
+}
+
+func (m MatrixRow) averageTTFB() time.Duration {
+	var total time.Duration
+	count := 0
+	for _, r := range m.Targets {
+		if r.TTFB > 0 {
+			total += r.TTFB
+			count++
+		}
+	}
+	if count == 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return total / time.Duration(count)
+}
+
+// TestProxyMatrix runs every proxy against every target, bounding total
+// in-flight downloads across the whole matrix at concurrent via a shared
+// semaphore rather than chunking each individual download.
+func TestProxyMatrix(names []string, proxies map[string]CProxy, targets []Target, concurrent int) map[string]map[string]Result {
+	if concurrent <= 0 {
+		concurrent = 1
+	}
+	sem := make(chan struct{}, concurrent)
+
+	matrix := make(map[string]map[string]Result, len(names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, name := range names {
+		row := make(map[string]Result, len(targets))
+		matrix[name] = row
+		proxy := proxies[name].Proxy
+
+		for _, target := range targets {
+			wg.Add(1)
+			go func(name string, proxy C.Proxy, target Target) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				result := TestProxyConcurrent(name, proxy, target.URL, target.Size, target.Timeout, 1)
+				mu.Lock()
+				row[target.Name] = *result
+				mu.Unlock()
+			}(name, proxy, target)
+		}
+	}
+	wg.Wait()
+	return matrix
+}
+
+// runMatrixMode replaces the single-liveness-object flow with a full
+// proxy x target matrix: every proxy is tested against every target, rows
+// are ranked by -sort (including the matrix-only "worst" mode), and the
+// wide-format result is printed and optionally written to CSV/YAML.
+func runMatrixMode(names []string, allProxies map[string]CProxy, targets []Target) {
+	matrix := TestProxyMatrix(names, allProxies, targets, *concurrent)
+
+	rows := make([]MatrixRow, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, MatrixRow{Name: name, Targets: matrix[name]})
+	}
+
+	switch *sortField {
+	case "worst":
+		sort.Slice(rows, func(i, j int) bool { return rows[i].WorstBandwidth() > rows[j].WorstBandwidth() })
+	case "b", "bandwidth":
+		sort.Slice(rows, func(i, j int) bool { return rows[i].totalBandwidth() > rows[j].totalBandwidth() })
+	case "t", "ttfb":
+		sort.Slice(rows, func(i, j int) bool { return rows[i].averageTTFB() < rows[j].averageTTFB() })
+	default:
+		logger.Fatal("unsupported sort field", zap.String("sort", *sortField))
+	}
+
+	targetNames := make([]string, 0, len(targets))
+	for _, t := range targets {
+		targetNames = append(targetNames, t.Name)
+	}
+
+	fmt.Printf("\n\n===多目标测速矩阵(按 %s 排序)===\n", *sortField)
+	header := fmt.Sprintf("%-42s", "节点")
+	for _, t := range targetNames {
+		header += fmt.Sprintf("\t%-12s\t%-12s", t+"带宽", t+"延迟")
+	}
+	fmt.Println(header)
+	for _, row := range rows {
+		line := fmt.Sprintf("%-42s", formatName(row.Name))
+		for _, t := range targetNames {
+			r := row.Targets[t]
+			line += fmt.Sprintf("\t%-12s\t%-12s", formatBandwidth(r.Bandwidth), formatMilliseconds(r.TTFB))
+		}
+		fmt.Println(line)
+	}
+
+	if strings.EqualFold(*output, "csv") {
+		if err := writeMatrixToCSV(*outfile+".csv", targetNames, rows); err != nil {
+			logger.Fatal("failed to write matrix csv", zap.Error(err))
+		}
+	} else if strings.EqualFold(*output, "yaml") {
+		if err := writeMatrixToYAML(*outfile+".yaml", targetNames, rows); err != nil {
+			logger.Fatal("failed to write matrix yaml", zap.Error(err))
+		}
+	}
+}
+
+func writeMatrixToCSV(filePath string, targetNames []string, rows []MatrixRow) error {
+	csvFile, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer csvFile.Close()
+
+	csvFile.WriteString("\xEF\xBB\xBF")
+
+	csvWriter := csv.NewWriter(csvFile)
+
+	header := []string{"节点"}
+	for _, t := range targetNames {
+		header = append(header, t+" 带宽 (MB/s)", t+" 延迟 (ms)")
+	}
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		line := []string{row.Name}
+		for _, t := range targetNames {
+			r := row.Targets[t]
+			line = append(line, fmt.Sprintf("%.2f", r.Bandwidth/1024/1024), strconv.FormatInt(r.TTFB.Milliseconds(), 10))
+		}
+		if err := csvWriter.Write(line); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// matrixYAMLRow is the wide-format report record written for -targets mode;
+// it's a results report, not a reusable Clash proxy config, since a matrix
+// of per-target bandwidth/TTFB doesn't fit that shape.
+type matrixYAMLRow struct {
+	Name    string                `yaml:"name"`
+	Targets map[string]yamlTarget `yaml:"targets"`
+}
+
+type yamlTarget struct {
+	BandwidthBytesPerSecond float64 `yaml:"bandwidth_bytes_per_second"`
+	TTFBMillis              int64   `yaml:"ttfb_ms"`
+}
+
+func writeMatrixToYAML(filePath string, targetNames []string, rows []MatrixRow) error {
+	fp, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	report := make([]matrixYAMLRow, 0, len(rows))
+	for _, row := range rows {
+		targets := make(map[string]yamlTarget, len(targetNames))
+		for _, t := range targetNames {
+			r := row.Targets[t]
+			targets[t] = yamlTarget{BandwidthBytesPerSecond: r.Bandwidth, TTFBMillis: r.TTFB.Milliseconds()}
+		}
+		report = append(report, matrixYAMLRow{Name: row.Name, Targets: targets})
+	}
+
+	marshaled, err := yaml.Marshal(report)
+	if err != nil {
+		return err
+	}
+	_, err = fp.Write(marshaled)
+	return err
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	C "github.com/Dreamacro/clash/constant"
+	"go.uber.org/zap"
+)
+
+// TestProxyUDP sends a burst of DNS A-record queries through the proxy's
+// UDP path and reports RTT median/p95, jitter (mean absolute deviation of
+// inter-arrival time) and packet loss. It exists because Hysteria2/TUIC/
+// WireGuard are UDP-native and the HTTP-over-TCP download in TestProxy
+// says little about how they behave under that profile.
+func TestProxyUDP(name string, proxy C.Proxy, target string, count int, interval time.Duration, timeout time.Duration) Result {
+	result := Result{Name: name, Bandwidth: -1, TTFB: -1, UDPLoss: 1, UDPRTT: -1, UDPRTTP95: -1, UDPJitter: -1}
+
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		logger.Warn("udp probe failed", zap.String("proxy.name", name), zap.String("stage", "bad-target"), zap.String("target", target), zap.Error(err))
+		return result
+	}
+	portNum, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		logger.Warn("udp probe failed", zap.String("proxy.name", name), zap.String("stage", "bad-target-port"), zap.String("target.port", port), zap.Error(err))
+		return result
+	}
+
+	remote, err := net.ResolveUDPAddr("udp", target)
+	if err != nil {
+		logger.Warn("udp probe failed", zap.String("proxy.name", name), zap.String("stage", "resolve"), zap.String("target", target), zap.Error(err))
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	pc, err := proxy.ListenPacketContext(ctx, &C.Metadata{
+		NetWork: C.UDP,
+		Host:    host,
+		DstPort: uint16(portNum),
+	})
+	if err != nil {
+		logger.Warn("udp probe failed", zap.String("proxy.name", name), zap.String("stage", "listen"), zap.Error(err))
+		return result
+	}
+	defer pc.Close()
+
+	rtts := make([]time.Duration, 0, count)
+	deviations := make([]float64, 0, count)
+	var lastArrival time.Time
+
+	for i := 0; i < count; i++ {
+		id := uint16(i + 1)
+		pc.SetReadDeadline(time.Now().Add(timeout))
+
+		start := time.Now()
+		if _, err := pc.WriteTo(buildDNSQuery(id, "www.gstatic.com"), remote); err != nil {
+			logger.Warn("udp probe failed", zap.String("proxy.name", name), zap.String("stage", "write"), zap.Int("query.index", i), zap.Error(err))
+			if i+1 < count {
+				time.Sleep(interval)
+			}
+			continue
+		}
+
+		buf := make([]byte, 512)
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil || n < 2 || binary.BigEndian.Uint16(buf[:2]) != id {
+			if i+1 < count {
+				time.Sleep(interval)
+			}
+			continue
+		}
+
+		arrival := time.Now()
+		rtts = append(rtts, arrival.Sub(start))
+		if !lastArrival.IsZero() {
+			deviations = append(deviations, float64(arrival.Sub(lastArrival)))
+		}
+		lastArrival = arrival
+
+		if i+1 < count {
+			time.Sleep(interval)
+		}
+	}
+
+	result.UDPLoss = float64(count-len(rtts)) / float64(count)
+	if len(rtts) == 0 {
+		return result
+	}
+
+	sort.Slice(rtts, func(i, j int) bool { return rtts[i] < rtts[j] })
+	result.UDPRTT = rtts[len(rtts)/2]
+	result.UDPRTTP95 = rtts[int(math.Ceil(float64(len(rtts))*0.95))-1]
+	result.UDPJitter = meanAbsDeviation(deviations)
+	return result
+}
+
+func meanAbsDeviation(samples []float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	mean := 0.0
+	for _, s := range samples {
+		mean += s
+	}
+	mean /= float64(len(samples))
+
+	deviation := 0.0
+	for _, s := range samples {
+		deviation += math.Abs(s - mean)
+	}
+	return time.Duration(deviation / float64(len(samples)))
+}
+
+// buildDNSQuery builds a minimal DNS A-record query for hostname, tagging
+// it with id so the response matching it can be correlated back to the
+// request it answers.
+func buildDNSQuery(id uint16, hostname string) []byte {
+	buf := make([]byte, 12, 12+len(hostname)+6)
+	binary.BigEndian.PutUint16(buf[0:2], id)
+	binary.BigEndian.PutUint16(buf[2:4], 0x0100) // recursion desired
+	binary.BigEndian.PutUint16(buf[4:6], 1)      // qdcount
+
+	for _, label := range strings.Split(hostname, ".") {
+		buf = append(buf, byte(len(label)))
+		buf = append(buf, label...)
+	}
+	buf = append(buf, 0)    // root label
+	buf = append(buf, 0, 1) // qtype A
+	buf = append(buf, 0, 1) // qclass IN
+	return buf
+}
+
+func formatUDPLoss(v float64) string {
+	if v < 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.01f%%", v*100)
+}
+
+func formatUDPDuration(v time.Duration) string {
+	if v < 0 {
+		return "N/A"
+	}
+	return formatMilliseconds(v)
+}